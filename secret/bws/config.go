@@ -15,6 +15,10 @@ type Config struct {
 	IdentityURL string
 	StateFile   string
 	CacheTTL    time.Duration
+	// NegativeCacheTTL bounds how long a "not found" lookup (unknown
+	// secret ID, missing project, or missing key) is cached before being
+	// retried upstream. Defaults to 30s.
+	NegativeCacheTTL time.Duration
 }
 
 func (c Config) withEnvDefaults() Config {
@@ -28,6 +32,9 @@ func (c Config) withEnvDefaults() Config {
 	if out.CacheTTL == 0 {
 		out.CacheTTL = 10 * time.Minute
 	}
+	if out.NegativeCacheTTL == 0 {
+		out.NegativeCacheTTL = 30 * time.Second
+	}
 	return out
 }
 
@@ -38,5 +45,8 @@ func (c Config) validateForInit() error {
 	if c.CacheTTL < 0 {
 		return errors.New("bws cache_ttl cannot be negative")
 	}
+	if c.NegativeCacheTTL < 0 {
+		return errors.New("bws negative_cache_ttl cannot be negative")
+	}
 	return nil
 }
@@ -42,6 +42,14 @@ func configFromMap(cfg map[string]any) (Config, error) {
 		out.CacheTTL = d
 	}
 
+	if v, ok := cfg["negative_cache_ttl"]; ok {
+		d, err := durationVal(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid negative_cache_ttl: %w", err)
+		}
+		out.NegativeCacheTTL = d
+	}
+
 	return out, nil
 }
 
@@ -11,8 +11,17 @@ import (
 	"time"
 
 	sdk "github.com/bitwarden/sdk-go"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/jonwraymond/toolops-integrations/secret/bws/template"
 )
 
+// errSecretNotFound marks errors produced by a definitive "not found"
+// response (unknown secret ID, missing project, missing key), as opposed to
+// a transient or configuration error. Only errors wrapping this sentinel are
+// eligible for negative caching.
+var errSecretNotFound = errors.New("bws: secret not found")
+
 type provider struct {
 	client bwsClient
 	logger *slog.Logger
@@ -20,8 +29,10 @@ type provider struct {
 
 	now func() time.Time
 
-	cache bwsCache
-	close sync.Once
+	cache         bwsCache
+	sf            singleflight.Group
+	syncStateFile string
+	close         sync.Once
 }
 
 type bwsCache struct {
@@ -30,6 +41,61 @@ type bwsCache struct {
 	projectByName map[string]string
 	secretByProj  map[string]map[string]string // projectID -> keyName -> secretID
 	cacheTTL      time.Duration
+
+	values      map[string]cachedValue // ref -> resolved secret value, TTL'd by cacheTTL
+	negative    map[string]time.Time   // ref -> expiry of a cached "not found"
+	negativeTTL time.Duration
+
+	// lastSyncedAt is the timestamp of the last successful cache refresh
+	// (full, or incremental via Secrets().Sync), or the zero value if the
+	// cache has never been populated. It is
+	// persisted to syncStatePath(Config.StateFile) so restarts can resume
+	// incremental sync instead of paying for a full refresh.
+	lastSyncedAt time.Time
+}
+
+type cachedValue struct {
+	value     string
+	expiresAt time.Time
+}
+
+// getValue returns a cached, unexpired value for ref, if any.
+func (c *bwsCache) getValue(ref string, now time.Time) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.values[ref]
+	if !ok || now.After(v.expiresAt) {
+		return "", false
+	}
+	return v.value, true
+}
+
+func (c *bwsCache) setValue(ref, value string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = map[string]cachedValue{}
+	}
+	c.values[ref] = cachedValue{value: value, expiresAt: now.Add(c.cacheTTL)}
+	delete(c.negative, ref)
+}
+
+// isNegative reports whether ref is covered by an unexpired negative cache
+// entry.
+func (c *bwsCache) isNegative(ref string, now time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	expiresAt, ok := c.negative[ref]
+	return ok && now.Before(expiresAt)
+}
+
+func (c *bwsCache) setNegative(ref string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.negative == nil {
+		c.negative = map[string]time.Time{}
+	}
+	c.negative[ref] = now.Add(c.negativeTTL)
 }
 
 // New creates a Bitwarden Secrets Manager provider.
@@ -72,15 +138,33 @@ func New(cfg Config, logger *slog.Logger) (*provider, error) {
 		orgID = strings.TrimSpace(os.Getenv("BWS_ORG_ID"))
 	}
 
+	syncFile := syncStatePath(cfg.StateFile)
+	state, err := loadSyncState(syncFile)
+	if err != nil && logger != nil {
+		logger.Warn("failed to load bws sync state, starting with a full refresh", "error", err)
+	}
+	projectByName, secretByProj := state.ProjectByName, state.SecretByProj
+	if projectByName == nil {
+		projectByName = map[string]string{}
+	}
+	if secretByProj == nil {
+		secretByProj = map[string]map[string]string{}
+	}
+
 	p := &provider{
-		client: client,
-		logger: logger,
-		orgID:  orgID,
-		now:    time.Now,
+		client:        client,
+		logger:        logger,
+		orgID:         orgID,
+		now:           time.Now,
+		syncStateFile: syncFile,
 		cache: bwsCache{
-			projectByName: map[string]string{},
-			secretByProj:  map[string]map[string]string{},
+			projectByName: projectByName,
+			secretByProj:  secretByProj,
+			values:        map[string]cachedValue{},
+			negative:      map[string]time.Time{},
 			cacheTTL:      cfg.CacheTTL,
+			negativeTTL:   cfg.NegativeCacheTTL,
+			lastSyncedAt:  state.LastSyncedAt,
 		},
 	}
 	return p, nil
@@ -88,14 +172,49 @@ func New(cfg Config, logger *slog.Logger) (*provider, error) {
 
 func (p *provider) Name() string { return "bws" }
 
+// Template returns a template.Renderer that resolves secret refs through p.
+// See package template for TemplateSpec and the Run/RenderOnce entry points.
+func (p *provider) Template(specs []template.TemplateSpec, opts ...template.Option) *template.Renderer {
+	return template.New(p, specs, opts...)
+}
+
 func (p *provider) Resolve(ctx context.Context, ref string) (string, error) {
 	trimmed := strings.TrimSpace(ref)
 	if trimmed == "" {
 		return "", errors.New("bws ref is empty")
 	}
 
+	now := p.nowFn()
+	if value, ok := p.cache.getValue(trimmed, now); ok {
+		return value, nil
+	}
+	if p.cache.isNegative(trimmed, now) {
+		return "", fmt.Errorf("bws ref %q: %w", trimmed, errSecretNotFound)
+	}
+
+	v, err, _ := p.sf.Do(trimmed, func() (any, error) {
+		return p.resolveUncached(ctx, trimmed)
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// resolveUncached performs the actual upstream lookup for ref. Concurrent
+// Resolve calls for the same ref collapse onto a single in-flight call via
+// p.sf, so this only runs once per ref at a time.
+func (p *provider) resolveUncached(ctx context.Context, trimmed string) (string, error) {
 	if projectName, keyName, ok := parseProjectKeyRef(trimmed); ok {
-		return p.resolveByProjectKey(ctx, projectName, keyName)
+		value, err := p.resolveByProjectKey(ctx, projectName, keyName)
+		if err != nil {
+			if errors.Is(err, errSecretNotFound) {
+				p.cache.setNegative(trimmed, p.nowFn())
+			}
+			return "", err
+		}
+		p.cache.setValue(trimmed, value, p.nowFn())
+		return value, nil
 	}
 
 	select {
@@ -106,11 +225,138 @@ func (p *provider) Resolve(ctx context.Context, ref string) (string, error) {
 
 	secret, err := p.client.Secrets().Get(trimmed)
 	if err != nil {
+		// The SDK doesn't distinguish "unknown secret ID" from a transient
+		// or network error in its returned error value, so — as with
+		// resolveByProjectKey's own Get call above — this is never marked
+		// not-found and never negative-cached; only a definitively missing
+		// project or key (looked up against our own cache) is.
 		return "", fmt.Errorf("bws get secret: %w", err)
 	}
+	p.cache.setValue(trimmed, secret.Value, p.nowFn())
 	return secret.Value, nil
 }
 
+// ResolveBatch resolves many refs with at most one Secrets().GetByIDS call of
+// its own, rather than one round trip per ref. Refs already covered by the
+// value cache are served from memory; project/.../key/... refs are translated
+// to secret IDs via the cache (ensuring it at most once; a full refresh there
+// makes its own List+GetByIDS calls); everything still unresolved after that
+// is fetched together in a single GetByIDS call.
+// Per-ref errors are returned alongside any successfully resolved values
+// rather than failing the whole batch. This is the method the secret
+// registry's optional batch-resolve interface type-asserts for, so bulk
+// dotenv exports can avoid Resolve's one-ref-at-a-time round trips.
+func (p *provider) ResolveBatch(ctx context.Context, refs []string) (map[string]string, map[string]error) {
+	values := make(map[string]string, len(refs))
+	errs := make(map[string]error)
+	now := p.nowFn()
+
+	type projectLookup struct {
+		ref, projectName, keyName string
+	}
+
+	var projectLookups []projectLookup
+	idToRefs := make(map[string][]string) // secret ID -> original refs that need its value
+
+	for _, ref := range refs {
+		trimmed := strings.TrimSpace(ref)
+		if trimmed == "" {
+			errs[ref] = errors.New("bws ref is empty")
+			continue
+		}
+		if value, ok := p.cache.getValue(trimmed, now); ok {
+			values[ref] = value
+			continue
+		}
+		if p.cache.isNegative(trimmed, now) {
+			errs[ref] = fmt.Errorf("bws ref %q: %w", trimmed, errSecretNotFound)
+			continue
+		}
+		if projectName, keyName, ok := parseProjectKeyRef(trimmed); ok {
+			projectLookups = append(projectLookups, projectLookup{ref: ref, projectName: projectName, keyName: keyName})
+			continue
+		}
+		idToRefs[trimmed] = append(idToRefs[trimmed], ref)
+	}
+
+	if len(projectLookups) > 0 {
+		if err := p.ensureCache(ctx); err != nil {
+			for _, pl := range projectLookups {
+				errs[pl.ref] = err
+			}
+			projectLookups = nil
+		}
+	}
+
+	for _, pl := range projectLookups {
+		p.cache.mu.RLock()
+		projectID, ok := p.cache.projectByName[pl.projectName]
+		var secretID string
+		if ok {
+			secretID, ok = p.cache.secretByProj[projectID][pl.keyName]
+		}
+		p.cache.mu.RUnlock()
+		if !ok {
+			errs[pl.ref] = fmt.Errorf("bws secret %q not found in project %q: %w", pl.keyName, pl.projectName, errSecretNotFound)
+			continue
+		}
+		idToRefs[secretID] = append(idToRefs[secretID], pl.ref)
+	}
+
+	if len(idToRefs) == 0 {
+		return values, errs
+	}
+
+	ids := make([]string, 0, len(idToRefs))
+	for id := range idToRefs {
+		ids = append(ids, id)
+	}
+
+	resp, err := p.client.Secrets().GetByIDS(ids)
+	if err != nil {
+		err = fmt.Errorf("bws get secrets: %w", err)
+		for _, batchRefs := range idToRefs {
+			for _, ref := range batchRefs {
+				errs[ref] = err
+			}
+		}
+		return values, errs
+	}
+
+	found := make(map[string]string, len(resp.Data))
+	for _, secret := range resp.Data {
+		found[secret.ID] = secret.Value
+	}
+
+	for id, batchRefs := range idToRefs {
+		value, ok := found[id]
+		if !ok {
+			p.cache.setNegative(id, now)
+			notFound := fmt.Errorf("bws secret id %q not found: %w", id, errSecretNotFound)
+			for _, ref := range batchRefs {
+				errs[ref] = notFound
+			}
+			continue
+		}
+		p.cache.setValue(id, value, now)
+		for _, ref := range batchRefs {
+			values[ref] = value
+			if trimmed := strings.TrimSpace(ref); trimmed != id {
+				p.cache.setValue(trimmed, value, now)
+			}
+		}
+	}
+
+	return values, errs
+}
+
+func (p *provider) nowFn() time.Time {
+	if p.now == nil {
+		return time.Now()
+	}
+	return p.now()
+}
+
 func (p *provider) Close() error {
 	p.close.Do(func() {
 		if p.client != nil {
@@ -119,7 +365,10 @@ func (p *provider) Close() error {
 		p.cache.mu.Lock()
 		p.cache.projectByName = map[string]string{}
 		p.cache.secretByProj = map[string]map[string]string{}
+		p.cache.values = map[string]cachedValue{}
+		p.cache.negative = map[string]time.Time{}
 		p.cache.expiresAt = time.Time{}
+		p.cache.lastSyncedAt = time.Time{}
 		p.cache.mu.Unlock()
 	})
 	return nil
@@ -158,7 +407,7 @@ func (p *provider) resolveByProjectKey(ctx context.Context, projectName, keyName
 		if p.logger != nil {
 			p.logger.Warn("bws project not found", "project", projectName, "key", keyName)
 		}
-		return "", fmt.Errorf("bws project %q not found", projectName)
+		return "", fmt.Errorf("bws project %q not found: %w", projectName, errSecretNotFound)
 	}
 	secrets := p.cache.secretByProj[projectID]
 	secretID, ok := secrets[keyName]
@@ -167,7 +416,7 @@ func (p *provider) resolveByProjectKey(ctx context.Context, projectName, keyName
 		if p.logger != nil {
 			p.logger.Warn("bws secret not found", "project", projectName, "key", keyName)
 		}
-		return "", fmt.Errorf("bws secret %q not found in project %q", keyName, projectName)
+		return "", fmt.Errorf("bws secret %q not found in project %q: %w", keyName, projectName, errSecretNotFound)
 	}
 
 	secret, err := p.client.Secrets().Get(secretID)
@@ -197,6 +446,11 @@ func (p *provider) ensureCache(ctx context.Context) error {
 	return p.refreshCache(ctx)
 }
 
+// refreshCache repopulates the project/secret cache. When a prior sync
+// timestamp is available it prefers the incremental Secrets().Sync path,
+// which costs one call instead of List+GetByIDS; it falls back to a full
+// refresh on first run or whenever the incremental sync itself fails (e.g.
+// the upstream sync token was invalidated).
 func (p *provider) refreshCache(ctx context.Context) error {
 	select {
 	case <-ctx.Done():
@@ -204,6 +458,64 @@ func (p *provider) refreshCache(ctx context.Context) error {
 	default:
 	}
 
+	p.cache.mu.RLock()
+	lastSyncedAt := p.cache.lastSyncedAt
+	p.cache.mu.RUnlock()
+
+	if !lastSyncedAt.IsZero() {
+		if err := p.incrementalRefresh(ctx, lastSyncedAt); err == nil {
+			return nil
+		} else if p.logger != nil {
+			p.logger.Warn("bws incremental sync failed, falling back to full refresh", "error", err)
+		}
+	}
+
+	return p.fullRefresh(ctx)
+}
+
+// incrementalRefresh calls Secrets().Sync and rebuilds secretByProj from its
+// response. Despite the name, Sync does not return a delta to merge: per the
+// SDK's own documentation, a HasChanges response carries every secret
+// currently accessible to the machine account, so secretByProj must be
+// rebuilt from resp.Secrets wholesale rather than merged into the existing
+// map — otherwise a secret deleted or moved upstream since the last sync
+// would stay in the cache forever under its old, now-invalid ID. Project names
+// rarely change and Sync does not report them, so projectByName is left
+// untouched here and only rebuilt by fullRefresh.
+func (p *provider) incrementalRefresh(ctx context.Context, lastSyncedAt time.Time) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	resp, err := p.client.Secrets().Sync(p.orgID, &lastSyncedAt)
+	if err != nil {
+		return fmt.Errorf("bws sync secrets: %w", err)
+	}
+
+	now := p.nowFn()
+
+	p.cache.mu.Lock()
+	defer p.cache.mu.Unlock()
+
+	if resp.HasChanges {
+		secretByProj := make(map[string]map[string]string)
+		for _, secret := range resp.Secrets {
+			applySecretDelta(secretByProj, secret)
+		}
+		p.cache.secretByProj = secretByProj
+	}
+	p.cache.expiresAt = now.Add(p.cache.cacheTTL)
+	p.cache.lastSyncedAt = now
+	p.persistSyncStateLocked()
+
+	return nil
+}
+
+// fullRefresh lists every project and secret and rebuilds the cache from
+// scratch. It is the only path that can discover new/renamed projects.
+func (p *provider) fullRefresh(ctx context.Context) error {
 	projects, err := p.client.Projects().List(p.orgID)
 	if err != nil {
 		return fmt.Errorf("bws list projects: %w", err)
@@ -233,26 +545,48 @@ func (p *provider) refreshCache(ctx context.Context) error {
 
 	secretByProj := make(map[string]map[string]string)
 	for _, secret := range secrets.Data {
-		if secret.ProjectID == nil {
-			continue
-		}
-		projectID := *secret.ProjectID
-		if secretByProj[projectID] == nil {
-			secretByProj[projectID] = make(map[string]string)
-		}
-		secretByProj[projectID][secret.Key] = secret.ID
+		applySecretDelta(secretByProj, secret)
 	}
 
-	now := p.now
-	if now == nil {
-		now = time.Now
-	}
+	now := p.nowFn()
 
 	p.cache.mu.Lock()
 	p.cache.projectByName = projectByName
 	p.cache.secretByProj = secretByProj
-	p.cache.expiresAt = now().Add(p.cache.cacheTTL)
+	p.cache.expiresAt = now.Add(p.cache.cacheTTL)
+	p.cache.lastSyncedAt = now
+	p.persistSyncStateLocked()
 	p.cache.mu.Unlock()
 
 	return nil
 }
+
+// applySecretDelta mutates secretByProj to reflect secret, as reported by
+// either a full list or an incremental sync.
+func applySecretDelta(secretByProj map[string]map[string]string, secret sdk.SecretResponse) {
+	if secret.ProjectID == nil {
+		return
+	}
+	projectID := *secret.ProjectID
+	if secretByProj[projectID] == nil {
+		secretByProj[projectID] = make(map[string]string)
+	}
+	secretByProj[projectID][secret.Key] = secret.ID
+}
+
+// persistSyncStateLocked snapshots the current cache to disk so a restarted
+// provider can resume incremental sync without an initial full refresh.
+// Callers must hold p.cache.mu.
+func (p *provider) persistSyncStateLocked() {
+	if p.syncStateFile == "" {
+		return
+	}
+	state := syncState{
+		LastSyncedAt:  p.cache.lastSyncedAt,
+		ProjectByName: p.cache.projectByName,
+		SecretByProj:  p.cache.secretByProj,
+	}
+	if err := saveSyncState(p.syncStateFile, state); err != nil && p.logger != nil {
+		p.logger.Warn("failed to persist bws sync state", "error", err)
+	}
+}
@@ -2,6 +2,8 @@ package bws
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -25,6 +27,7 @@ func (c *fakeClient) Close() {
 }
 
 type fakeSecrets struct {
+	mu            sync.Mutex
 	getCalls      []string
 	listCalls     []string
 	getByIDsCalls [][]string
@@ -32,51 +35,167 @@ type fakeSecrets struct {
 	getResp      map[string]*sdk.SecretResponse
 	listResp     *sdk.SecretIdentifiersResponse
 	getByIDsResp *sdk.SecretsResponse
+
+	// getDelay, when set, is slept inside Get before returning, so tests
+	// can force concurrent Resolve calls to overlap.
+	getDelay time.Duration
+	getErr   error
+
+	syncCalls []fakeSyncCall
+	syncResp  *sdk.SecretsSyncResponse
+	syncErr   error
+
+	createCalls []fakeCreateCall
+	createErr   error
+	updateCalls []fakeUpdateCall
+	updateErr   error
+	deleteCalls [][]string
+	deleteErr   error
 }
 
-func (s *fakeSecrets) Create(string, string, string, string, []string) (*sdk.SecretResponse, error) {
-	panic("not used")
+type fakeCreateCall struct {
+	key, value, note, orgID string
+	projectIDs              []string
+}
+
+type fakeUpdateCall struct {
+	id, key, value, note, orgID string
+	projectIDs                  []string
+}
+
+type fakeSyncCall struct {
+	orgID        string
+	lastSyncedAt *time.Time
+}
+
+func (s *fakeSecrets) Create(key, value, note, orgID string, projectIDs []string) (*sdk.SecretResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.createCalls = append(s.createCalls, fakeCreateCall{key: key, value: value, note: note, orgID: orgID, projectIDs: projectIDs})
+	if s.createErr != nil {
+		return nil, s.createErr
+	}
+	id := fmt.Sprintf("created-secret-%d", len(s.createCalls))
+	var projectID *string
+	if len(projectIDs) > 0 {
+		pid := projectIDs[0]
+		projectID = &pid
+	}
+	return &sdk.SecretResponse{ID: id, Key: key, Value: value, Note: note, ProjectID: projectID}, nil
 }
 func (s *fakeSecrets) List(orgID string) (*sdk.SecretIdentifiersResponse, error) {
+	s.mu.Lock()
 	s.listCalls = append(s.listCalls, orgID)
+	s.mu.Unlock()
 	if s.listResp == nil {
 		return &sdk.SecretIdentifiersResponse{}, nil
 	}
 	return s.listResp, nil
 }
 func (s *fakeSecrets) Get(id string) (*sdk.SecretResponse, error) {
+	s.mu.Lock()
 	s.getCalls = append(s.getCalls, id)
-	if s.getResp != nil {
-		if resp, ok := s.getResp[id]; ok {
-			return resp, nil
-		}
+	delay := s.getDelay
+	err := s.getErr
+	s.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	resp, ok := s.getResp[id]
+	s.mu.Unlock()
+	if ok {
+		return resp, nil
 	}
 	return &sdk.SecretResponse{ID: id, Value: ""}, nil
 }
 func (s *fakeSecrets) GetByIDS(ids []string) (*sdk.SecretsResponse, error) {
 	cp := make([]string, len(ids))
 	copy(cp, ids)
+	s.mu.Lock()
 	s.getByIDsCalls = append(s.getByIDsCalls, cp)
+	s.mu.Unlock()
 	if s.getByIDsResp == nil {
 		return &sdk.SecretsResponse{}, nil
 	}
 	return s.getByIDsResp, nil
 }
-func (s *fakeSecrets) Update(string, string, string, string, string, []string) (*sdk.SecretResponse, error) {
-	panic("not used")
+func (s *fakeSecrets) Update(id, key, value, note, orgID string, projectIDs []string) (*sdk.SecretResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.updateCalls = append(s.updateCalls, fakeUpdateCall{id: id, key: key, value: value, note: note, orgID: orgID, projectIDs: projectIDs})
+	if s.updateErr != nil {
+		return nil, s.updateErr
+	}
+	var projectID *string
+	if len(projectIDs) > 0 {
+		pid := projectIDs[0]
+		projectID = &pid
+	}
+	return &sdk.SecretResponse{ID: id, Key: key, Value: value, Note: note, ProjectID: projectID}, nil
 }
-func (s *fakeSecrets) Delete([]string) (*sdk.SecretsDeleteResponse, error) { panic("not used") }
-func (s *fakeSecrets) Sync(string, *time.Time) (*sdk.SecretsSyncResponse, error) {
-	panic("not used")
+func (s *fakeSecrets) Delete(ids []string) (*sdk.SecretsDeleteResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := make([]string, len(ids))
+	copy(cp, ids)
+	s.deleteCalls = append(s.deleteCalls, cp)
+	if s.deleteErr != nil {
+		return nil, s.deleteErr
+	}
+	return &sdk.SecretsDeleteResponse{}, nil
+}
+func (s *fakeSecrets) Sync(orgID string, lastSyncedAt *time.Time) (*sdk.SecretsSyncResponse, error) {
+	s.mu.Lock()
+	s.syncCalls = append(s.syncCalls, fakeSyncCall{orgID: orgID, lastSyncedAt: lastSyncedAt})
+	s.mu.Unlock()
+	if s.syncErr != nil {
+		return nil, s.syncErr
+	}
+	if s.syncResp == nil {
+		return &sdk.SecretsSyncResponse{}, nil
+	}
+	return s.syncResp, nil
+}
+
+func (s *fakeSecrets) getCallCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.getCalls)
+}
+
+func (s *fakeSecrets) syncCallCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.syncCalls)
 }
 
 type fakeProjects struct {
-	listCalls []string
-	listResp  *sdk.ProjectsResponse
+	mu          sync.Mutex
+	listCalls   []string
+	listResp    *sdk.ProjectsResponse
+	createCalls [][2]string
+	createErr   error
 }
 
-func (p *fakeProjects) Create(string, string) (*sdk.ProjectResponse, error) { panic("not used") }
+func (p *fakeProjects) Create(orgID, name string) (*sdk.ProjectResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.createCalls = append(p.createCalls, [2]string{orgID, name})
+	if p.createErr != nil {
+		return nil, p.createErr
+	}
+	id := fmt.Sprintf("created-project-%d", len(p.createCalls))
+	return &sdk.ProjectResponse{ID: id, Name: name, OrganizationID: orgID}, nil
+}
 func (p *fakeProjects) List(orgID string) (*sdk.ProjectsResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.listCalls = append(p.listCalls, orgID)
 	if p.listResp == nil {
 		return &sdk.ProjectsResponse{}, nil
@@ -248,8 +367,384 @@ func TestBWSProvider_CacheTTL_RefreshesAfterExpiry(t *testing.T) {
 	if _, err := p.Resolve(ctx, "project/dotenv/key/"+keyName); err != nil {
 		t.Fatalf("Resolve returned error: %v", err)
 	}
-	if len(projects.listCalls) != 2 {
-		t.Fatalf("expected refresh after TTL, got %d", len(projects.listCalls))
+	// The refresh after TTL expiry now prefers the incremental
+	// Secrets().Sync path over a full Projects().List + Secrets().List +
+	// GetByIDS, so Projects().List is not called again.
+	if len(projects.listCalls) != 1 {
+		t.Fatalf("expected refresh after TTL to use Sync, not List, got %d List calls", len(projects.listCalls))
+	}
+	if got := secrets.syncCallCount(); got != 1 {
+		t.Fatalf("expected refresh after TTL to call Sync once, got %d", got)
+	}
+}
+
+func TestBWSProvider_Resolve_ConcurrentCallsCollapseToOneUpstreamCall(t *testing.T) {
+	ctx := context.Background()
+
+	secrets := &fakeSecrets{
+		getDelay: 20 * time.Millisecond,
+		getResp: map[string]*sdk.SecretResponse{
+			"secret-id": {ID: "secret-id", Value: "s3cr3t"},
+		},
+	}
+	client := &fakeClient{secrets: secrets, projects: &fakeProjects{}}
+
+	p := &provider{
+		client: client,
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{},
+			secretByProj:  map[string]map[string]string{},
+			values:        map[string]cachedValue{},
+			negative:      map[string]time.Time{},
+			cacheTTL:      10 * time.Minute,
+			negativeTTL:   30 * time.Second,
+		},
+	}
+
+	const n = 20
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	vals := make([]string, n)
+	for i := range n {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			vals[i], errs[i] = p.Resolve(ctx, "secret-id")
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Resolve[%d] returned error: %v", i, err)
+		}
+		if vals[i] != "s3cr3t" {
+			t.Fatalf("Resolve[%d] returned %q", i, vals[i])
+		}
+	}
+	if got := secrets.getCallCount(); got != 1 {
+		t.Fatalf("expected exactly one Get call to reach the fake client, got %d", got)
+	}
+}
+
+func TestBWSProvider_Resolve_NegativeCachesMissingProjectOrKey(t *testing.T) {
+	ctx := context.Background()
+
+	secrets := &fakeSecrets{}
+	projects := &fakeProjects{}
+	client := &fakeClient{secrets: secrets, projects: projects}
+
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{"dotenv": "p1"},
+			secretByProj:  map[string]map[string]string{"p1": {}},
+			values:        map[string]cachedValue{},
+			negative:      map[string]time.Time{},
+			cacheTTL:      10 * time.Minute,
+			negativeTTL:   time.Minute,
+			expiresAt:     time.Now().Add(10 * time.Minute),
+		},
+	}
+
+	ref := "project/dotenv/key/MISSING_KEY"
+	if _, err := p.Resolve(ctx, ref); !errors.Is(err, errSecretNotFound) {
+		t.Fatalf("expected errSecretNotFound for missing key, got: %v", err)
+	}
+	if _, err := p.Resolve(ctx, ref); !errors.Is(err, errSecretNotFound) {
+		t.Fatalf("expected errSecretNotFound from negative cache, got: %v", err)
+	}
+	if got := len(projects.listCalls) + len(secrets.listCalls); got != 0 {
+		t.Fatalf("expected the cache to already be populated, no refresh expected, got %d calls", got)
+	}
+}
+
+// Raw secret-ID lookups hit the SDK's Secrets().Get, whose error value
+// doesn't distinguish "unknown ID" from a transient or network failure.
+// Resolve must not negative-cache these: a misbehaving upstream would
+// otherwise get treated as a confirmed not-found for NegativeCacheTTL.
+func TestBWSProvider_Resolve_RawSecretIDGetErrorNotNegativeCached(t *testing.T) {
+	ctx := context.Background()
+
+	secrets := &fakeSecrets{getErr: errors.New("upstream timeout: connection reset")}
+	client := &fakeClient{secrets: secrets, projects: &fakeProjects{}}
+
+	p := &provider{
+		client: client,
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{},
+			secretByProj:  map[string]map[string]string{},
+			values:        map[string]cachedValue{},
+			negative:      map[string]time.Time{},
+			cacheTTL:      10 * time.Minute,
+			negativeTTL:   time.Minute,
+		},
+	}
+
+	if _, err := p.Resolve(ctx, "missing-id"); errors.Is(err, errSecretNotFound) {
+		t.Fatalf("expected a plain error for a transient Get failure, not errSecretNotFound: %v", err)
+	}
+	if _, err := p.Resolve(ctx, "missing-id"); err == nil {
+		t.Fatalf("expected error for unresolvable secret id")
+	}
+	if got := secrets.getCallCount(); got != 2 {
+		t.Fatalf("expected no negative caching, so a second Resolve should call Get again, got %d calls", got)
+	}
+}
+
+func TestBWSProvider_EnsureCache_SecondRefreshAfterTTLUsesSync(t *testing.T) {
+	ctx := context.Background()
+
+	projectID := "p1"
+	secretID := "s1"
+	keyName := "TAVILY_API_KEY"
+
+	secrets := &fakeSecrets{
+		listResp: &sdk.SecretIdentifiersResponse{Data: []sdk.SecretIdentifierResponse{
+			{ID: secretID, Key: keyName, OrganizationID: "org"},
+		}},
+		getByIDsResp: &sdk.SecretsResponse{Data: []sdk.SecretResponse{
+			{ID: secretID, Key: keyName, ProjectID: &projectID, Value: "ignored"},
+		}},
+		syncResp: &sdk.SecretsSyncResponse{HasChanges: false},
+	}
+	projects := &fakeProjects{
+		listResp: &sdk.ProjectsResponse{Data: []sdk.ProjectResponse{
+			{ID: projectID, Name: "dotenv", OrganizationID: "org"},
+		}},
+	}
+	client := &fakeClient{secrets: secrets, projects: projects}
+
+	var nowMu sync.Mutex
+	now := time.Date(2026, 2, 6, 12, 0, 0, 0, time.UTC)
+	nowFn := func() time.Time {
+		nowMu.Lock()
+		defer nowMu.Unlock()
+		return now
+	}
+
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    nowFn,
+		cache: bwsCache{
+			projectByName: map[string]string{},
+			secretByProj:  map[string]map[string]string{},
+			cacheTTL:      10 * time.Minute,
+		},
+	}
+
+	if err := p.ensureCache(ctx); err != nil {
+		t.Fatalf("first ensureCache returned error: %v", err)
+	}
+	if got := len(projects.listCalls); got != 1 {
+		t.Fatalf("expected first refresh to List projects once, got %d", got)
+	}
+	if got := secrets.syncCallCount(); got != 0 {
+		t.Fatalf("expected no Sync call on first (full) refresh, got %d", got)
+	}
+
+	nowMu.Lock()
+	now = now.Add(11 * time.Minute)
+	nowMu.Unlock()
+
+	if err := p.ensureCache(ctx); err != nil {
+		t.Fatalf("second ensureCache returned error: %v", err)
+	}
+	if got := len(projects.listCalls); got != 1 {
+		t.Fatalf("expected second refresh to prefer Sync, but Projects().List was called %d times", got)
+	}
+	if got := len(secrets.listCalls); got != 1 {
+		t.Fatalf("expected second refresh to prefer Sync, but Secrets().List was called %d times", got)
+	}
+	if got := len(secrets.getByIDsCalls); got != 1 {
+		t.Fatalf("expected second refresh to prefer Sync, but Secrets().GetByIDS was called %d times", got)
+	}
+	if got := secrets.syncCallCount(); got != 1 {
+		t.Fatalf("expected second refresh to call Sync exactly once, got %d", got)
+	}
+}
+
+func BenchmarkProvider_EnsureCache_IncrementalRefresh(b *testing.B) {
+	projectID := "p1"
+	secretID := "s1"
+
+	secrets := &fakeSecrets{
+		syncResp: &sdk.SecretsSyncResponse{
+			HasChanges: true,
+			Secrets: []sdk.SecretResponse{
+				{ID: secretID, Key: "TAVILY_API_KEY", ProjectID: &projectID},
+			},
+		},
+	}
+	projects := &fakeProjects{}
+	client := &fakeClient{secrets: secrets, projects: projects}
+
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{"dotenv": projectID},
+			secretByProj:  map[string]map[string]string{},
+			cacheTTL:      0,
+			lastSyncedAt:  time.Now(),
+		},
+	}
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := p.incrementalRefresh(ctx, p.cache.lastSyncedAt); err != nil {
+			b.Fatalf("incrementalRefresh returned error: %v", err)
+		}
+	}
+}
+
+func TestBWSProvider_IncrementalRefresh_DropsSecretsAbsentFromSync(t *testing.T) {
+	projectID := "p1"
+
+	secrets := &fakeSecrets{
+		syncResp: &sdk.SecretsSyncResponse{
+			HasChanges: true,
+			Secrets:    nil,
+		},
+	}
+	client := &fakeClient{secrets: secrets, projects: &fakeProjects{}}
+
+	lastSyncedAt := time.Date(2026, 2, 6, 12, 0, 0, 0, time.UTC)
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    func() time.Time { return lastSyncedAt.Add(time.Minute) },
+		cache: bwsCache{
+			projectByName: map[string]string{"dotenv": projectID},
+			secretByProj: map[string]map[string]string{
+				projectID: {"DELETED_KEY": "old-secret-id"},
+			},
+			cacheTTL:     10 * time.Minute,
+			lastSyncedAt: lastSyncedAt,
+		},
+	}
+
+	if err := p.incrementalRefresh(context.Background(), lastSyncedAt); err != nil {
+		t.Fatalf("incrementalRefresh returned error: %v", err)
+	}
+
+	if _, ok := p.cache.secretByProj[projectID]["DELETED_KEY"]; ok {
+		t.Fatalf("expected DELETED_KEY to be dropped from the cache after a Sync response that no longer includes it")
+	}
+}
+
+func TestBWSProvider_ResolveBatch_SingleGetByIDSForMixedBatch(t *testing.T) {
+	ctx := context.Background()
+
+	projectID := "p1"
+
+	// Pre-populate the project/secret index so the batch call doesn't need
+	// to trigger ensureCache's own List+GetByIDS refresh; this isolates the
+	// GetByIDS call made by ResolveBatch itself.
+	secretByProj := map[string]map[string]string{
+		projectID: {
+			"SUPABASE_ACCESS_TOKEN": "proj-secret-1",
+			"TAVILY_API_KEY":        "proj-secret-2",
+		},
+	}
+
+	getByIDsResp := &sdk.SecretsResponse{}
+	for i := 0; i < 8; i++ {
+		id := fmt.Sprintf("raw-id-%d", i)
+		getByIDsResp.Data = append(getByIDsResp.Data, sdk.SecretResponse{ID: id, Value: "v-" + id})
+	}
+	getByIDsResp.Data = append(getByIDsResp.Data,
+		sdk.SecretResponse{ID: "proj-secret-1", ProjectID: &projectID, Value: "token-1"},
+		sdk.SecretResponse{ID: "proj-secret-2", ProjectID: &projectID, Value: "token-2"},
+	)
+
+	secrets := &fakeSecrets{getByIDsResp: getByIDsResp}
+	client := &fakeClient{secrets: secrets, projects: &fakeProjects{}}
+
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{"dotenv": projectID},
+			secretByProj:  secretByProj,
+			cacheTTL:      10 * time.Minute,
+			expiresAt:     time.Now().Add(10 * time.Minute),
+		},
+	}
+
+	refs := []string{
+		"project/dotenv/key/SUPABASE_ACCESS_TOKEN",
+		"project/dotenv/key/TAVILY_API_KEY",
+	}
+	for i := 0; i < 8; i++ {
+		refs = append(refs, fmt.Sprintf("raw-id-%d", i))
+	}
+
+	values, errs := p.ResolveBatch(ctx, refs)
+	if len(errs) != 0 {
+		t.Fatalf("expected no errors, got: %#v", errs)
+	}
+	if len(values) != len(refs) {
+		t.Fatalf("expected %d resolved values, got %d: %#v", len(refs), len(values), values)
+	}
+	if values["project/dotenv/key/SUPABASE_ACCESS_TOKEN"] != "token-1" {
+		t.Fatalf("unexpected value for SUPABASE_ACCESS_TOKEN: %q", values["project/dotenv/key/SUPABASE_ACCESS_TOKEN"])
+	}
+	if values["raw-id-3"] != "v-raw-id-3" {
+		t.Fatalf("unexpected value for raw-id-3: %q", values["raw-id-3"])
+	}
+	if got := len(secrets.getByIDsCalls); got != 1 {
+		t.Fatalf("expected exactly one GetByIDS call for the whole batch, got %d", got)
+	}
+	if got := len(secrets.getByIDsCalls[0]); got != 10 {
+		t.Fatalf("expected the single GetByIDS call to cover all 10 ids, got %d", got)
+	}
+}
+
+func TestBWSProvider_ResolveBatch_PartialErrorForUnknownRef(t *testing.T) {
+	ctx := context.Background()
+
+	secrets := &fakeSecrets{
+		getByIDsResp: &sdk.SecretsResponse{Data: []sdk.SecretResponse{
+			{ID: "known-id", Value: "known-value"},
+		}},
+	}
+	client := &fakeClient{secrets: secrets, projects: &fakeProjects{}}
+
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{},
+			secretByProj:  map[string]map[string]string{},
+			cacheTTL:      10 * time.Minute,
+		},
+	}
+
+	values, errs := p.ResolveBatch(ctx, []string{"known-id", "unknown-id"})
+	if values["known-id"] != "known-value" {
+		t.Fatalf("expected known-id to resolve, got values=%#v", values)
+	}
+	if _, ok := values["unknown-id"]; ok {
+		t.Fatalf("expected unknown-id to have no value, got values=%#v", values)
+	}
+	if err := errs["unknown-id"]; !errors.Is(err, errSecretNotFound) {
+		t.Fatalf("expected errSecretNotFound for unknown-id, got: %v", err)
+	}
+	if err := errs["known-id"]; err != nil {
+		t.Fatalf("expected no error for known-id, got: %v", err)
+	}
+	if got := len(secrets.getByIDsCalls); got != 1 {
+		t.Fatalf("expected a single GetByIDS call, got %d", got)
 	}
 }
 
@@ -0,0 +1,69 @@
+package bws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// syncState is the on-disk snapshot of the project/secret cache plus the
+// timestamp of the last successful cache refresh, so a restarted
+// provider can resume incremental sync instead of falling back to a full
+// refresh.
+type syncState struct {
+	LastSyncedAt  time.Time                    `json:"last_synced_at"`
+	ProjectByName map[string]string            `json:"project_by_name,omitempty"`
+	SecretByProj  map[string]map[string]string `json:"secret_by_proj,omitempty"`
+}
+
+// syncStatePath derives the sync state file path from the bitwarden login
+// state file, keeping the two JSON blobs side by side. Returns "" when
+// stateFile is unset, which disables persistence.
+func syncStatePath(stateFile string) string {
+	if strings.TrimSpace(stateFile) == "" {
+		return ""
+	}
+	return stateFile + ".bws-sync.json"
+}
+
+func loadSyncState(path string) (syncState, error) {
+	if strings.TrimSpace(path) == "" {
+		return syncState{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return syncState{}, nil
+	}
+	if err != nil {
+		return syncState{}, fmt.Errorf("read bws sync state: %w", err)
+	}
+	var s syncState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return syncState{}, fmt.Errorf("parse bws sync state: %w", err)
+	}
+	return s, nil
+}
+
+// saveSyncState writes state to path via a temp file + rename, matching the
+// atomic-write pattern used for rendered templates.
+func saveSyncState(path string, s syncState) error {
+	if strings.TrimSpace(path) == "" {
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshal bws sync state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write bws sync state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write bws sync state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+// Package template renders Go text/template files that reference secrets by
+// ref (e.g. `{{ secret "project/dotenv/key/SUPABASE_ACCESS_TOKEN" }}` or
+// `{{ secret "<secret-id>" }}`) into an on-disk destination, in the spirit of
+// Nomad's consul-template integration.
+package template
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// SecretResolver resolves a secret reference to its value. secret.Provider
+// (and so bws's provider) satisfies this interface.
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// Signaler is a caller-supplied process handle that Renderer delivers
+// signals to after a re-render. *os.Process satisfies this interface.
+type Signaler interface {
+	Signal(os.Signal) error
+}
+
+// TemplateSpec describes one template to render.
+type TemplateSpec struct {
+	// Source is the path to the text/template source file.
+	Source string
+	// Destination is where the rendered output is written.
+	Destination string
+	// Perms are the file permissions applied to Destination. Defaults to
+	// 0o644 when zero.
+	Perms os.FileMode
+	// Command, if set, is exec'd after a render that changes Destination.
+	Command []string
+	// Signal, if set, is sent via the Renderer's Signaler after a render
+	// that changes Destination.
+	Signal os.Signal
+	// Splay jitters Command/Signal delivery by up to this duration, to
+	// avoid thundering herds when many instances re-render at once.
+	Splay time.Duration
+}
+
+// Option configures a Renderer.
+type Option func(*Renderer)
+
+// WithCacheTTL sets the poll interval used by Run. Defaults to 1 minute.
+func WithCacheTTL(d time.Duration) Option {
+	return func(r *Renderer) { r.cacheTTL = d }
+}
+
+// WithProcess supplies the process that Signal-bearing specs are delivered
+// to.
+func WithProcess(p Signaler) Option {
+	return func(r *Renderer) { r.proc = p }
+}
+
+// WithRefreshSignal makes Run re-render immediately whenever ch fires, in
+// addition to its CacheTTL poll, so Run can be driven by provider cache
+// refresh events rather than a fixed interval alone.
+func WithRefreshSignal(ch <-chan struct{}) Option {
+	return func(r *Renderer) { r.refresh = ch }
+}
+
+// WithLogger overrides the default logger.
+func WithLogger(l *slog.Logger) Option {
+	return func(r *Renderer) { r.logger = l }
+}
+
+// Renderer renders a set of TemplateSpecs, re-rendering a spec only when its
+// resolved secret values change.
+type Renderer struct {
+	resolver SecretResolver
+	specs    []TemplateSpec
+	cacheTTL time.Duration
+	proc     Signaler
+	refresh  <-chan struct{}
+	logger   *slog.Logger
+
+	mu       sync.Mutex
+	lastHash map[string]string // Source -> hash of rendered ref=value pairs
+
+	randFloat func() float64 // splay jitter source, overridable in tests
+	sleep     func(time.Duration)
+}
+
+// New creates a Renderer for the given specs, resolving secret refs through
+// resolver.
+func New(resolver SecretResolver, specs []TemplateSpec, opts ...Option) *Renderer {
+	r := &Renderer{
+		resolver:  resolver,
+		specs:     specs,
+		cacheTTL:  time.Minute,
+		logger:    slog.Default(),
+		lastHash:  map[string]string{},
+		randFloat: rand.Float64,
+		sleep:     time.Sleep,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// RenderOnce renders every spec exactly once and returns, for CI-style use.
+func (r *Renderer) RenderOnce(ctx context.Context) error {
+	var errs []error
+	for _, spec := range r.specs {
+		if _, err := r.renderOne(ctx, spec); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", spec.Source, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run blocks, re-rendering specs whenever the resolved secret values change.
+// It polls every CacheTTL and, if WithRefreshSignal was supplied, also
+// re-renders as soon as a refresh is signaled. Run returns when ctx is
+// canceled.
+func (r *Renderer) Run(ctx context.Context) error {
+	if err := r.RenderOnce(ctx); err != nil {
+		r.logger.Warn("initial template render failed", "error", err)
+	}
+
+	ticker := time.NewTicker(r.cacheTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-r.refresh:
+		}
+		if err := r.RenderOnce(ctx); err != nil {
+			r.logger.Warn("template render failed", "error", err)
+		}
+	}
+}
+
+// renderOne renders a single spec, writing Destination only if the resolved
+// ref=value set changed since the last render.
+func (r *Renderer) renderOne(ctx context.Context, spec TemplateSpec) (changed bool, err error) {
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	name := filepath.Base(spec.Source)
+	refValues := map[string]string{}
+	tmpl, err := template.New(name).Funcs(template.FuncMap{
+		"secret": func(ref string) (string, error) {
+			val, rerr := r.resolver.Resolve(ctx, ref)
+			if rerr != nil {
+				return "", rerr
+			}
+			refValues[ref] = val
+			return val, nil
+		},
+	}).ParseFiles(spec.Source)
+	if err != nil {
+		return false, fmt.Errorf("parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, nil); err != nil {
+		return false, fmt.Errorf("render template: %w", err)
+	}
+
+	hash := hashRefValues(refValues)
+
+	r.mu.Lock()
+	prev, ok := r.lastHash[spec.Source]
+	r.mu.Unlock()
+	if ok && prev == hash {
+		return false, nil
+	}
+
+	if err := atomicWrite(spec.Destination, buf.Bytes(), spec.Perms); err != nil {
+		return false, fmt.Errorf("write destination: %w", err)
+	}
+
+	r.mu.Lock()
+	r.lastHash[spec.Source] = hash
+	r.mu.Unlock()
+
+	if err := r.notify(ctx, spec); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// notify jitters by up to Splay, then execs Command and/or delivers Signal.
+func (r *Renderer) notify(ctx context.Context, spec TemplateSpec) error {
+	if len(spec.Command) == 0 && spec.Signal == nil {
+		return nil
+	}
+
+	if spec.Splay > 0 {
+		jitter := time.Duration(r.randFloat() * float64(spec.Splay))
+		r.sleep(jitter)
+	}
+
+	if len(spec.Command) > 0 {
+		cmd := exec.CommandContext(ctx, spec.Command[0], spec.Command[1:]...)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("exec command: %w", err)
+		}
+	}
+
+	if spec.Signal != nil {
+		if r.proc == nil {
+			return fmt.Errorf("signal %v requested but no process was configured", spec.Signal)
+		}
+		if err := r.proc.Signal(spec.Signal); err != nil {
+			return fmt.Errorf("signal process: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// atomicWrite writes data to dest via a temp file in the same directory,
+// chmod, then os.Rename, so readers never observe a partial write.
+func atomicWrite(dest string, data []byte, perms os.FileMode) error {
+	if perms == 0 {
+		perms = 0o644
+	}
+
+	dir := filepath.Dir(dest)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(dest)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perms); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, dest); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// hashRefValues returns a stable hash over the sorted ref=value pairs
+// resolved during a template render, used to detect whether a re-render
+// would actually change anything.
+func hashRefValues(refValues map[string]string) string {
+	keys := make([]string, 0, len(refValues))
+	for k := range refValues {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, refValues[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,164 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type fakeResolver struct {
+	mu     sync.Mutex
+	values map[string]string
+	calls  []string
+}
+
+func (f *fakeResolver) Resolve(_ context.Context, ref string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, ref)
+	val, ok := f.values[ref]
+	if !ok {
+		return "", fmt.Errorf("fake resolver: unknown ref %q", ref)
+	}
+	return val, nil
+}
+
+type fakeSignaler struct {
+	mu      sync.Mutex
+	signals []os.Signal
+}
+
+func (f *fakeSignaler) Signal(sig os.Signal) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.signals = append(f.signals, sig)
+	return nil
+}
+
+func (f *fakeSignaler) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.signals)
+}
+
+func newTestRenderer(t *testing.T, resolver SecretResolver, specs []TemplateSpec, opts ...Option) *Renderer {
+	t.Helper()
+	r := New(resolver, specs, opts...)
+	r.randFloat = func() float64 { return 0 }
+	r.sleep = func(_ time.Duration) {}
+	return r
+}
+
+func writeSource(t *testing.T, dir, contents string) string {
+	t.Helper()
+	path := filepath.Join(dir, "tmpl.tpl")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("write template source: %v", err)
+	}
+	return path
+}
+
+func TestRenderer_RenderOnce_InitialRender(t *testing.T) {
+	dir := t.TempDir()
+	source := writeSource(t, dir, `TOKEN={{ secret "project/dotenv/key/TOKEN" }}`)
+	dest := filepath.Join(dir, "out.env")
+
+	resolver := &fakeResolver{values: map[string]string{"project/dotenv/key/TOKEN": "s3cr3t"}}
+	spec := TemplateSpec{Source: source, Destination: dest, Perms: 0o600}
+	r := newTestRenderer(t, resolver, []TemplateSpec{spec})
+
+	if err := r.RenderOnce(context.Background()); err != nil {
+		t.Fatalf("RenderOnce returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("read destination: %v", err)
+	}
+	if string(got) != "TOKEN=s3cr3t" {
+		t.Fatalf("unexpected destination contents: %q", got)
+	}
+}
+
+func TestRenderer_RenderOnce_NoopWhenUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	source := writeSource(t, dir, `TOKEN={{ secret "project/dotenv/key/TOKEN" }}`)
+	dest := filepath.Join(dir, "out.env")
+
+	resolver := &fakeResolver{values: map[string]string{"project/dotenv/key/TOKEN": "s3cr3t"}}
+	signaler := &fakeSignaler{}
+	spec := TemplateSpec{Source: source, Destination: dest, Signal: syscall.SIGHUP}
+	r := newTestRenderer(t, resolver, []TemplateSpec{spec}, WithProcess(signaler))
+
+	if err := r.RenderOnce(context.Background()); err != nil {
+		t.Fatalf("first RenderOnce returned error: %v", err)
+	}
+	if err := r.RenderOnce(context.Background()); err != nil {
+		t.Fatalf("second RenderOnce returned error: %v", err)
+	}
+
+	if got := signaler.count(); got != 1 {
+		t.Fatalf("expected exactly one signal for unchanged values, got %d", got)
+	}
+}
+
+func TestRenderer_RenderOnce_RerendersAndSignalsOnChange(t *testing.T) {
+	dir := t.TempDir()
+	source := writeSource(t, dir, `TOKEN={{ secret "project/dotenv/key/TOKEN" }}`)
+	dest := filepath.Join(dir, "out.env")
+
+	resolver := &fakeResolver{values: map[string]string{"project/dotenv/key/TOKEN": "first"}}
+	signaler := &fakeSignaler{}
+	spec := TemplateSpec{Source: source, Destination: dest, Signal: syscall.SIGHUP}
+	r := newTestRenderer(t, resolver, []TemplateSpec{spec}, WithProcess(signaler))
+
+	if err := r.RenderOnce(context.Background()); err != nil {
+		t.Fatalf("first RenderOnce returned error: %v", err)
+	}
+
+	resolver.mu.Lock()
+	resolver.values["project/dotenv/key/TOKEN"] = "second"
+	resolver.mu.Unlock()
+
+	if err := r.RenderOnce(context.Background()); err != nil {
+		t.Fatalf("second RenderOnce returned error: %v", err)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("read destination: %v", err)
+	}
+	if string(got) != "TOKEN=second" {
+		t.Fatalf("unexpected destination contents after change: %q", got)
+	}
+	if count := signaler.count(); count != 2 {
+		t.Fatalf("expected two signals across two changing renders, got %d", count)
+	}
+}
+
+func TestRenderer_RenderOnce_DestinationPermissions(t *testing.T) {
+	dir := t.TempDir()
+	source := writeSource(t, dir, `TOKEN={{ secret "project/dotenv/key/TOKEN" }}`)
+	dest := filepath.Join(dir, "out.env")
+
+	resolver := &fakeResolver{values: map[string]string{"project/dotenv/key/TOKEN": "s3cr3t"}}
+	spec := TemplateSpec{Source: source, Destination: dest, Perms: 0o600}
+	r := newTestRenderer(t, resolver, []TemplateSpec{spec})
+
+	if err := r.RenderOnce(context.Background()); err != nil {
+		t.Fatalf("RenderOnce returned error: %v", err)
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatalf("stat destination: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("expected perms 0600, got %o", perm)
+	}
+}
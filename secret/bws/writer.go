@@ -0,0 +1,282 @@
+package bws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	sdk "github.com/bitwarden/sdk-go"
+)
+
+// ImportOptions controls how ImportDotenv treats keys that already exist in
+// the target project, or that exist there but are absent from the kv map
+// being imported.
+type ImportOptions struct {
+	// Overwrite updates existing secrets, skipping only those whose cached
+	// value already equals kv's; a secret whose value is not in the cache is
+	// always updated. Without it, existing keys are left untouched.
+	Overwrite bool
+	// Prune deletes secrets present in the project but absent from kv.
+	Prune bool
+	// DryRun computes the ImportResult without calling Create/Update/Delete.
+	DryRun bool
+}
+
+// ImportResult summarizes what ImportDotenv did (or, under DryRun, would
+// do), keyed by secret key name.
+type ImportResult struct {
+	Created []string
+	Updated []string
+	Skipped []string
+	Deleted []string
+}
+
+// Writer exposes the bwsClient's write-side (Create/Update/Delete) API for
+// secrets and projects. It shares client, orgID and cache with the provider
+// it was created from, so a write is immediately visible to that
+// provider's Resolve/ResolveBatch calls without a cache invalidation.
+type Writer struct {
+	p *provider
+}
+
+// Writer returns a Writer sharing p's client and cache.
+func (p *provider) Writer() *Writer {
+	return &Writer{p: p}
+}
+
+// EnsureProject returns the ID of the project named name, creating it if it
+// doesn't already exist.
+func (w *Writer) EnsureProject(ctx context.Context, name string) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+	}
+
+	p := w.p
+	if strings.TrimSpace(p.orgID) == "" {
+		return "", errors.New("bws organization id is required to write projects")
+	}
+
+	if err := p.ensureCache(ctx); err != nil {
+		return "", err
+	}
+
+	p.cache.mu.RLock()
+	id, ok := p.cache.projectByName[name]
+	p.cache.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	project, err := p.client.Projects().Create(p.orgID, name)
+	if err != nil {
+		return "", fmt.Errorf("bws create project: %w", err)
+	}
+
+	p.cache.mu.Lock()
+	p.cache.projectByName[name] = project.ID
+	if p.cache.secretByProj[project.ID] == nil {
+		p.cache.secretByProj[project.ID] = map[string]string{}
+	}
+	p.persistSyncStateLocked()
+	p.cache.mu.Unlock()
+
+	return project.ID, nil
+}
+
+// PutSecret creates or updates the secret named key in projectName, setting
+// its value and note. On success the in-memory cache is updated in place
+// rather than invalidated, so a subsequent Resolve sees the new value
+// immediately without a round trip.
+func (w *Writer) PutSecret(ctx context.Context, projectName, key, value, note string) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+	}
+
+	p := w.p
+	if strings.TrimSpace(p.orgID) == "" {
+		return "", errors.New("bws organization id is required to write secrets")
+	}
+
+	projectID, err := w.EnsureProject(ctx, projectName)
+	if err != nil {
+		return "", err
+	}
+
+	p.cache.mu.RLock()
+	secretID, exists := p.cache.secretByProj[projectID][key]
+	p.cache.mu.RUnlock()
+
+	var secret *sdk.SecretResponse
+	if exists {
+		secret, err = p.client.Secrets().Update(secretID, key, value, note, p.orgID, []string{projectID})
+		if err != nil {
+			return "", fmt.Errorf("bws update secret: %w", err)
+		}
+	} else {
+		secret, err = p.client.Secrets().Create(key, value, note, p.orgID, []string{projectID})
+		if err != nil {
+			return "", fmt.Errorf("bws create secret: %w", err)
+		}
+	}
+
+	now := p.nowFn()
+
+	p.cache.mu.Lock()
+	if p.cache.secretByProj[projectID] == nil {
+		p.cache.secretByProj[projectID] = map[string]string{}
+	}
+	p.cache.secretByProj[projectID][key] = secret.ID
+	p.persistSyncStateLocked()
+	p.cache.mu.Unlock()
+
+	p.cache.setValue(secret.ID, value, now)
+	p.cache.setValue(fmt.Sprintf("project/%s/key/%s", projectName, key), value, now)
+
+	return secret.ID, nil
+}
+
+// DeleteSecret deletes the secret identified by ref, which may be either a
+// raw secret ID or a project/.../key/... reference.
+func (w *Writer) DeleteSecret(ctx context.Context, ref string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	p := w.p
+	trimmed := strings.TrimSpace(ref)
+	if trimmed == "" {
+		return errors.New("bws ref is empty")
+	}
+
+	secretID := trimmed
+	if projectName, keyName, ok := parseProjectKeyRef(trimmed); ok {
+		if err := p.ensureCache(ctx); err != nil {
+			return err
+		}
+		p.cache.mu.RLock()
+		projectID, ok := p.cache.projectByName[projectName]
+		var found bool
+		if ok {
+			secretID, found = p.cache.secretByProj[projectID][keyName]
+		}
+		p.cache.mu.RUnlock()
+		if !ok || !found {
+			return fmt.Errorf("bws secret %q not found in project %q: %w", keyName, projectName, errSecretNotFound)
+		}
+	}
+
+	if _, err := p.client.Secrets().Delete([]string{secretID}); err != nil {
+		return fmt.Errorf("bws delete secret: %w", err)
+	}
+
+	p.cache.mu.Lock()
+	for name, projectID := range p.cache.projectByName {
+		for key, id := range p.cache.secretByProj[projectID] {
+			if id == secretID {
+				delete(p.cache.values, fmt.Sprintf("project/%s/key/%s", name, key))
+			}
+		}
+	}
+	for _, keys := range p.cache.secretByProj {
+		for key, id := range keys {
+			if id == secretID {
+				delete(keys, key)
+			}
+		}
+	}
+	delete(p.cache.values, secretID)
+	delete(p.cache.values, trimmed)
+	p.persistSyncStateLocked()
+	p.cache.mu.Unlock()
+
+	return nil
+}
+
+// ImportDotenv syncs kv into projectName as secrets, creating missing keys
+// and, depending on opts, overwriting changed values and pruning keys that
+// are no longer present in kv.
+func (w *Writer) ImportDotenv(ctx context.Context, projectName string, kv map[string]string, opts ImportOptions) (ImportResult, error) {
+	var result ImportResult
+
+	p := w.p
+	var projectID string
+	if opts.DryRun {
+		if err := p.ensureCache(ctx); err != nil {
+			return result, err
+		}
+		p.cache.mu.RLock()
+		projectID = p.cache.projectByName[projectName]
+		p.cache.mu.RUnlock()
+	} else {
+		var err error
+		projectID, err = w.EnsureProject(ctx, projectName)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	p.cache.mu.RLock()
+	existing := make(map[string]string, len(p.cache.secretByProj[projectID]))
+	for key, id := range p.cache.secretByProj[projectID] {
+		existing[key] = id
+	}
+	p.cache.mu.RUnlock()
+
+	for key, value := range kv {
+		secretID, ok := existing[key]
+		if !ok {
+			result.Created = append(result.Created, key)
+			if !opts.DryRun {
+				if _, err := w.PutSecret(ctx, projectName, key, value, ""); err != nil {
+					return result, fmt.Errorf("create %s: %w", key, err)
+				}
+			}
+			continue
+		}
+
+		if !opts.Overwrite {
+			result.Skipped = append(result.Skipped, key)
+			continue
+		}
+		if current, ok := p.cache.getValue(secretID, p.nowFn()); ok && current == value {
+			result.Skipped = append(result.Skipped, key)
+			continue
+		}
+
+		result.Updated = append(result.Updated, key)
+		if !opts.DryRun {
+			if _, err := w.PutSecret(ctx, projectName, key, value, ""); err != nil {
+				return result, fmt.Errorf("update %s: %w", key, err)
+			}
+		}
+	}
+
+	if opts.Prune {
+		for key, secretID := range existing {
+			if _, keep := kv[key]; keep {
+				continue
+			}
+			result.Deleted = append(result.Deleted, key)
+			if !opts.DryRun {
+				if err := w.DeleteSecret(ctx, secretID); err != nil {
+					return result, fmt.Errorf("prune %s: %w", key, err)
+				}
+			}
+		}
+	}
+
+	sort.Strings(result.Created)
+	sort.Strings(result.Updated)
+	sort.Strings(result.Skipped)
+	sort.Strings(result.Deleted)
+
+	return result, nil
+}
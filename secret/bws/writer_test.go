@@ -0,0 +1,230 @@
+package bws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdk "github.com/bitwarden/sdk-go"
+)
+
+func TestWriter_PutSecret_CreatesWhenAbsent(t *testing.T) {
+	ctx := context.Background()
+
+	projects := &fakeProjects{
+		listResp: &sdk.ProjectsResponse{Data: []sdk.ProjectResponse{
+			{ID: "p1", Name: "dotenv", OrganizationID: "org"},
+		}},
+	}
+	secrets := &fakeSecrets{}
+	client := &fakeClient{secrets: secrets, projects: projects}
+
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{},
+			secretByProj:  map[string]map[string]string{},
+			cacheTTL:      10 * time.Minute,
+		},
+	}
+
+	id, err := p.Writer().PutSecret(ctx, "dotenv", "TAVILY_API_KEY", "s3cr3t", "from import")
+	if err != nil {
+		t.Fatalf("PutSecret returned error: %v", err)
+	}
+	if id == "" {
+		t.Fatalf("expected a non-empty secret id")
+	}
+	if len(secrets.createCalls) != 1 {
+		t.Fatalf("expected one Create call, got %d", len(secrets.createCalls))
+	}
+
+	got, err := p.Resolve(ctx, "project/dotenv/key/TAVILY_API_KEY")
+	if err != nil {
+		t.Fatalf("Resolve after PutSecret returned error: %v", err)
+	}
+	if got != "s3cr3t" {
+		t.Fatalf("expected Resolve to see the new value without a round trip, got %q", got)
+	}
+	if secrets.getCallCount() != 0 {
+		t.Fatalf("expected Resolve to be served from cache, but Get was called")
+	}
+}
+
+func TestWriter_PutSecret_UpdatesWhenPresent(t *testing.T) {
+	ctx := context.Background()
+
+	projectID := "p1"
+	secrets := &fakeSecrets{}
+	projects := &fakeProjects{
+		listResp: &sdk.ProjectsResponse{Data: []sdk.ProjectResponse{
+			{ID: projectID, Name: "dotenv", OrganizationID: "org"},
+		}},
+	}
+	client := &fakeClient{secrets: secrets, projects: projects}
+
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{"dotenv": projectID},
+			secretByProj:  map[string]map[string]string{projectID: {"TAVILY_API_KEY": "existing-id"}},
+			cacheTTL:      10 * time.Minute,
+			expiresAt:     time.Now().Add(10 * time.Minute),
+		},
+	}
+
+	id, err := p.Writer().PutSecret(ctx, "dotenv", "TAVILY_API_KEY", "new-value", "")
+	if err != nil {
+		t.Fatalf("PutSecret returned error: %v", err)
+	}
+	if id != "existing-id" {
+		t.Fatalf("expected Update to preserve the existing id, got %q", id)
+	}
+	if len(secrets.updateCalls) != 1 || len(secrets.createCalls) != 0 {
+		t.Fatalf("expected exactly one Update and no Create, got update=%d create=%d",
+			len(secrets.updateCalls), len(secrets.createCalls))
+	}
+}
+
+func TestWriter_DeleteSecret_RemovesFromCache(t *testing.T) {
+	ctx := context.Background()
+
+	projectID := "p1"
+	secrets := &fakeSecrets{}
+	projects := &fakeProjects{}
+	client := &fakeClient{secrets: secrets, projects: projects}
+
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{"dotenv": projectID},
+			secretByProj:  map[string]map[string]string{projectID: {"TAVILY_API_KEY": "secret-id"}},
+			cacheTTL:      10 * time.Minute,
+			expiresAt:     time.Now().Add(10 * time.Minute),
+		},
+	}
+
+	if err := p.Writer().DeleteSecret(ctx, "project/dotenv/key/TAVILY_API_KEY"); err != nil {
+		t.Fatalf("DeleteSecret returned error: %v", err)
+	}
+	if len(secrets.deleteCalls) != 1 || len(secrets.deleteCalls[0]) != 1 || secrets.deleteCalls[0][0] != "secret-id" {
+		t.Fatalf("expected Delete([secret-id]), got %#v", secrets.deleteCalls)
+	}
+
+	p.cache.mu.RLock()
+	_, stillPresent := p.cache.secretByProj[projectID]["TAVILY_API_KEY"]
+	p.cache.mu.RUnlock()
+	if stillPresent {
+		t.Fatalf("expected deleted key to be removed from the cache in place")
+	}
+}
+
+func TestWriter_ImportDotenv_CreatesUpdatesSkipsAndPrunes(t *testing.T) {
+	ctx := context.Background()
+
+	projectID := "p1"
+	secrets := &fakeSecrets{}
+	projects := &fakeProjects{
+		listResp: &sdk.ProjectsResponse{Data: []sdk.ProjectResponse{
+			{ID: projectID, Name: "dotenv", OrganizationID: "org"},
+		}},
+	}
+	client := &fakeClient{secrets: secrets, projects: projects}
+
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{"dotenv": projectID},
+			secretByProj: map[string]map[string]string{projectID: {
+				"KEEP_SAME":    "keep-id",
+				"NEEDS_UPDATE": "update-id",
+				"TO_BE_PRUNED": "prune-id",
+			}},
+			values: map[string]cachedValue{
+				"keep-id":   {value: "same-value", expiresAt: time.Now().Add(time.Hour)},
+				"update-id": {value: "old-value", expiresAt: time.Now().Add(time.Hour)},
+			},
+			cacheTTL:  10 * time.Minute,
+			expiresAt: time.Now().Add(10 * time.Minute),
+		},
+	}
+
+	kv := map[string]string{
+		"KEEP_SAME":    "same-value",
+		"NEEDS_UPDATE": "new-value",
+		"BRAND_NEW":    "brand-new-value",
+	}
+
+	result, err := p.Writer().ImportDotenv(ctx, "dotenv", kv, ImportOptions{Overwrite: true, Prune: true})
+	if err != nil {
+		t.Fatalf("ImportDotenv returned error: %v", err)
+	}
+
+	assertStrings(t, "Created", result.Created, []string{"BRAND_NEW"})
+	assertStrings(t, "Updated", result.Updated, []string{"NEEDS_UPDATE"})
+	assertStrings(t, "Skipped", result.Skipped, []string{"KEEP_SAME"})
+	assertStrings(t, "Deleted", result.Deleted, []string{"TO_BE_PRUNED"})
+
+	if len(secrets.deleteCalls) != 1 || secrets.deleteCalls[0][0] != "prune-id" {
+		t.Fatalf("expected prune-id to be deleted, got %#v", secrets.deleteCalls)
+	}
+}
+
+func TestWriter_ImportDotenv_DryRunMakesNoChanges(t *testing.T) {
+	ctx := context.Background()
+
+	projectID := "p1"
+	secrets := &fakeSecrets{}
+	projects := &fakeProjects{
+		listResp: &sdk.ProjectsResponse{Data: []sdk.ProjectResponse{
+			{ID: projectID, Name: "dotenv", OrganizationID: "org"},
+		}},
+	}
+	client := &fakeClient{secrets: secrets, projects: projects}
+
+	p := &provider{
+		client: client,
+		orgID:  "org",
+		now:    time.Now,
+		cache: bwsCache{
+			projectByName: map[string]string{"dotenv": projectID},
+			secretByProj:  map[string]map[string]string{projectID: {"EXISTING": "existing-id"}},
+			cacheTTL:      10 * time.Minute,
+			expiresAt:     time.Now().Add(10 * time.Minute),
+		},
+	}
+
+	kv := map[string]string{"EXISTING": "changed-value", "NEW_KEY": "v"}
+	result, err := p.Writer().ImportDotenv(ctx, "dotenv", kv, ImportOptions{Overwrite: true, Prune: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("ImportDotenv returned error: %v", err)
+	}
+
+	assertStrings(t, "Created", result.Created, []string{"NEW_KEY"})
+	assertStrings(t, "Updated", result.Updated, []string{"EXISTING"})
+
+	if len(secrets.createCalls) != 0 || len(secrets.updateCalls) != 0 || len(secrets.deleteCalls) != 0 {
+		t.Fatalf("expected DryRun to make no upstream calls, got create=%d update=%d delete=%d",
+			len(secrets.createCalls), len(secrets.updateCalls), len(secrets.deleteCalls))
+	}
+}
+
+func assertStrings(t *testing.T, label string, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %v, got %v", label, want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: expected %v, got %v", label, want, got)
+		}
+	}
+}